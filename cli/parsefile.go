@@ -28,47 +28,85 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"syscall"
 
-	"github.com/Comcast/gots"
 	"github.com/Comcast/gots/ebp"
+	"github.com/Comcast/gots/events"
+	"github.com/Comcast/gots/metrics"
 	"github.com/Comcast/gots/packet"
 	"github.com/Comcast/gots/packet/adaptationfield"
-	"github.com/Comcast/gots/pes"
 	"github.com/Comcast/gots/psi"
 	"github.com/Comcast/gots/scte35"
+	"github.com/Comcast/gots/scte35/avail"
+	"github.com/Comcast/gots/timing"
 )
 
-// main parses a ts file that is provided with the -f flag
+// formatText and formatJSON are the values accepted by the -format flag.
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+// main parses a ts stream that is provided with the -f flag
 func main() {
-	fileName := flag.String("f", "", "Required: Path to TS file to read")
-	outName := flag.String("o", "", "Path to TS file to write")
+	fileName := flag.String("f", "", "Required: Path to TS file to read, \"-\" for stdin, or a udp://host:port or rtp://host:port live source")
+	outName := flag.String("o", "", "Path to TS file to write, \"-\" for stdout, or a udp://host:port live sink")
 	showPmt := flag.Bool("pmt", true, "Output PMT info")
 	showEbp := flag.Bool("ebp", false, "Output EBP info. This is a lot of info")
 	showTiming := flag.Bool("timing", false, "Output timing info")
 	dumpSCTE35 := flag.Bool("scte35", false, "Output SCTE35 signals and info.")
+	showSCTE35Avails := flag.Bool("scte35-avails", false, "Correlate SCTE35 out/in descriptor pairs into avails and report open/closed ones")
 	showPacketNumberOfPID := flag.Int("pid", 0, "Dump the contents of the first packet encountered on PID to stdout")
+	format := flag.String("format", formatText, "Output format: text or json (NDJSON, one event per line)")
+	metricsAddr := flag.String("metrics", "", "Serve Prometheus metrics on this address (e.g. :9090) instead of exiting after the stream ends")
+	metricsOTLP := flag.String("metrics-otlp", "", "Also push metrics to an OTLP/gRPC collector at this address (e.g. localhost:4317)")
 	flag.Parse()
 	if *fileName == "" {
 		flag.Usage()
 		return
 	}
-	tsFile, err := os.Open(*fileName)
+	if *format != formatText && *format != formatJSON {
+		printlnf("Unknown -format %q, must be %q or %q", *format, formatText, formatJSON)
+		return
+	}
+
+	var m *metrics.Metrics
+	var otlpShutdown func(context.Context) error
+	if *metricsAddr != "" {
+		m = metrics.New()
+		if *metricsOTLP != "" {
+			shutdown, err := m.EnableOTLP(context.Background(), *metricsOTLP)
+			if err != nil {
+				printlnf("Cannot enable OTLP export to %s: %v", *metricsOTLP, err)
+				return
+			}
+			otlpShutdown = shutdown
+		}
+		go func() {
+			if err := m.ListenAndServe(*metricsAddr); err != nil {
+				fmt.Fprintln(os.Stderr, "metrics server stopped:", err)
+			}
+		}()
+	}
+	src, err := packet.Open(*fileName)
 	if err != nil {
-		printlnf("Cannot access test asset %s.", fileName)
+		printlnf("Cannot access source %s: %v", *fileName, err)
 		return
 	}
-	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
-			fmt.Println("Cannot close File", file.Name(), err)
+	defer func(src packet.Source) {
+		if err := src.Close(); err != nil {
+			fmt.Println("Cannot close source", *fileName, err)
 		}
-	}(tsFile)
+	}(src)
 	// Verify if sync-byte is present and seek to the first sync-byte
-	reader := bufio.NewReader(tsFile)
+	reader := bufio.NewReader(src)
 	_, err = packet.Sync(reader)
 	if err != nil {
 		fmt.Println(err)
@@ -79,7 +117,7 @@ func main() {
 		println(err)
 		return
 	}
-	printPat(pat)
+	printPat(*format, pat)
 
 	var pmts []psi.PMT
 	pm := pat.ProgramMap()
@@ -94,7 +132,7 @@ func main() {
 		}
 		pmts = append(pmts, pmt)
 		if *showPmt {
-			printPmt(pn, pmt)
+			printPmt(*format, pn, pmt)
 		}
 	}
 
@@ -102,7 +140,7 @@ func main() {
 	var numPackets uint64
 	ebps := make(map[uint64]ebp.EncoderBoundaryPoint)
 	scte35PIDs := make(map[uint16]bool)
-	if *dumpSCTE35 {
+	if *dumpSCTE35 || *showSCTE35Avails {
 		for _, pmt := range pmts {
 			for _, es := range pmt.ElementaryStreams() {
 				if es.StreamType() == psi.PmtStreamTypeScte35 {
@@ -114,19 +152,18 @@ func main() {
 		}
 	}
 
-	var outFile *os.File
+	var sink packet.Sink
 	if *outName != "" {
-		outFile, err = os.OpenFile(*outName, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
+		sink, err = packet.OpenSink(*outName)
 		if err != nil {
-			printlnf("Cannot open output file %s: %v", *outFile, err)
+			printlnf("Cannot open output %s: %v", *outName, err)
 			return
 		}
-		defer outFile.Close()
+		defer sink.Close()
 	}
 
-	var prevPCR, prevNewPCR uint64
-	prevPTS := make(map[uint16]uint64, 0)
-	var currentOffset, lastOffset int64
+	remapper := timing.NewDefaultRemapper()
+	correlator := avail.NewCorrelator()
 
 	for {
 		if _, err := io.ReadFull(reader, pkt); err != nil {
@@ -137,7 +174,10 @@ func main() {
 			return
 		}
 		numPackets++
-		if *dumpSCTE35 {
+		if m != nil {
+			m.ObservePacket(pkt)
+		}
+		if *dumpSCTE35 || *showSCTE35Avails {
 			currPID, err := packet.Pid(pkt)
 			if err != nil {
 				printlnf("Cannot get packet PID for %d", currPID)
@@ -154,8 +194,17 @@ func main() {
 					printlnf("Cannot parse SCTE35 Error=%v", err)
 					continue
 				}
-				printSCTE35(currPID, msg)
-
+				if m != nil {
+					m.ObserveSCTE35(scte35.SpliceCommandTypeNames[msg.CommandInfo().CommandType()])
+				}
+				if *dumpSCTE35 {
+					printSCTE35(*format, currPID, msg)
+				}
+				if *showSCTE35Avails {
+					for _, closed := range correlator.Feed(currPID, msg) {
+						printAvail(*format, closed)
+					}
+				}
 			}
 
 		}
@@ -172,7 +221,14 @@ func main() {
 				continue
 			}
 			ebps[numPackets] = boundaryPoint
-			printlnf("Packet %d contains EBP %+v", numPackets, boundaryPoint)
+			if m != nil {
+				m.ObserveEBP()
+			}
+			if *format == formatJSON {
+				emitJSON(events.NewEBP(numPackets, boundaryPoint))
+			} else {
+				printlnf("Packet %d contains EBP %+v", numPackets, boundaryPoint)
+			}
 		}
 		if *showPacketNumberOfPID != 0 {
 			pid := uint16(*showPacketNumberOfPID)
@@ -186,73 +242,90 @@ func main() {
 			}
 		}
 		if *showTiming {
-			currPID, _ := packet.Pid(pkt)
+			hasPCR := false
 			if ad, _ := packet.ContainsAdaptationField(pkt); ad {
-				if adaptationfield.HasPCR(pkt) {
-					pcrBytes, _ := adaptationfield.PCR(pkt)
-					pcr := gots.ExtractPCR(pcrBytes)
-
-					if prevPCR == 0 && currentOffset == 0 {
-						currentOffset = -int64(pcr) + (1 * gots.PcrClockRate)
-					} else if prevPCR != 0 && (pcr > prevPCR+2*gots.PcrClockRate || pcr < prevPCR) {
-						printlnf("PCR discontinuity detected! (%v -> %v)", prevPCR, pcr)
-						lastOffset = currentOffset
-						currentOffset = -int64(pcr) + int64(prevNewPCR) + (0.25 * gots.PcrClockRate)
-					}
-					prevPCR = pcr
-
-					newPCR := gots.PCR(pcr).Add(gots.PCR(currentOffset))
-					gots.InsertPCR(pcrBytes, uint64(newPCR))
-					prevNewPCR = uint64(newPCR)
-
-					printlnf("pid %v: PCR = %.4f -> %.4f (%v -> %v)", currPID, float64(pcr)/gots.PcrClockRate, float64(newPCR)/gots.PcrClockRate, pcr, newPCR)
-				}
+				hasPCR = adaptationfield.HasPCR(pkt)
 			}
-
-			if es, err := packet.PESHeader(pkt); err == nil {
-				h, err := pes.NewPESHeader(es)
-				if err == nil && h.HasPTS() {
-					pts := h.PTS()
-
-					prev := prevPTS[currPID]
-					if prevPCR == 0 && currentOffset == 0 {
-						currentOffset = -int64(pts*300) + (1 * gots.PcrClockRate)
-					}
-					if prev != 0 && (pts > prev+gots.PtsClockRate || pts < prev-gots.PtsClockRate) {
-						printlnf("PTS discontinuity detected!")
-					}
-					prevPTS[currPID] = pts
-
-					newPTS := gots.PTS(pts).Add(gots.PTS(currentOffset / 300))
-					if prevNewPCR != 0 && uint64(newPTS) > (prevNewPCR/300)+2*gots.PtsClockRate {
-						newPTS = gots.PTS(pts).Add(gots.PTS(lastOffset / 300))
-					}
-					gots.InsertPTS(es[9:14], uint64(newPTS))
-					printlnf("pid %v: PTS = %.4f -> %.4f (%v -> %v)", currPID, float64(pts)/gots.PtsClockRate, float64(newPTS)/gots.PtsClockRate, pts, newPTS)
-
+			statsBefore := remapper.Stats()
+			if _, err := remapper.Feed(pkt); err != nil {
+				printlnf("Cannot remap packet %d: %v", numPackets, err)
+			}
+			stats := remapper.Stats()
+			pcrDiscontinuity := stats.PCRDiscontinuities != statsBefore.PCRDiscontinuities
+			ptsDiscontinuity := stats.PTSDiscontinuities != statsBefore.PTSDiscontinuities
+			if m != nil {
+				if hasPCR {
+					pcr, _ := remapper.LastPCR()
+					m.ObservePCR(mustPid(pkt), pcr, pcrDiscontinuity)
 				}
-				if err == nil && h.HasDTS() && h.DTS() != 0 {
-					dts := h.DTS()
-
-					newDTS := gots.PTS(dts).Add(gots.PTS(currentOffset / 300))
-					if uint64(newDTS) > (prevNewPCR/300)+2*gots.PtsClockRate {
-						newDTS = gots.PTS(dts).Add(gots.PTS(lastOffset / 300))
-					}
-					gots.InsertPTS(es[14:19], uint64(newDTS))
-
-					printlnf("pid %v: DTS = %.4f -> %.4f (%v -> %v)", currPID, float64(dts)/gots.PtsClockRate, float64(newDTS)/gots.PtsClockRate, dts, newDTS)
+				if ptsDiscontinuity {
+					m.ObservePTSDiscontinuity()
+				}
+			}
+			if *format == formatJSON {
+				if hasPCR {
+					pcr, newPCR := remapper.LastPCR()
+					emitJSON(events.NewPCR(mustPid(pkt), pcr, newPCR, pcrDiscontinuity))
 				}
+			} else if pcrDiscontinuity || ptsDiscontinuity {
+				printlnf("pid %v: timing discontinuity detected (PCR=%d PTS=%d so far)", mustPid(pkt), stats.PCRDiscontinuities, stats.PTSDiscontinuities)
 			}
 		}
-		if outFile != nil {
-			outFile.Write(pkt)
+		if sink != nil {
+			sink.Write(pkt)
+		}
+	}
+	if *showTiming {
+		stats := remapper.Stats()
+		// Keep stdout pure NDJSON in json mode.
+		summaryOut := io.Writer(os.Stdout)
+		if *format == formatJSON {
+			summaryOut = os.Stderr
+		}
+		fmt.Fprintf(summaryOut, "Timing remap summary: %d PCR discontinuities, %d PTS discontinuities, total offset applied %d\n", stats.PCRDiscontinuities, stats.PTSDiscontinuities, stats.TotalOffsetApplied)
+	}
+	if *showSCTE35Avails {
+		for _, open := range correlator.OpenAvails() {
+			printAvail(*format, open)
 		}
 	}
 	println()
 
+	if *metricsAddr != "" {
+		fmt.Fprintln(os.Stderr, "Stream processing complete; still serving metrics on", *metricsAddr, "until interrupted")
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+	}
+	if otlpShutdown != nil {
+		if err := otlpShutdown(context.Background()); err != nil {
+			fmt.Fprintln(os.Stderr, "Error shutting down OTLP exporter:", err)
+		}
+	}
+}
+
+func printAvail(format string, a avail.Avail) {
+	if format == formatJSON {
+		emitJSON(events.NewAvail(a))
+		return
+	}
+	if a.Closed {
+		printlnf("Avail %s/%d on PID %d closed: declared %v, measured %v (delta %v)", a.TypeID, a.EventID, a.Pid, a.DeclaredDuration, a.MeasuredDuration, a.DurationDelta)
+	} else {
+		printlnf("Avail %s/%d on PID %d still open (opened at PTS %d)", a.TypeID, a.EventID, a.Pid, a.OutPTS)
+	}
+}
+
+func mustPid(pkt packet.Packet) uint16 {
+	pid, _ := packet.Pid(pkt)
+	return pid
 }
 
-func printSCTE35(pid uint16, msg scte35.SCTE35) {
+func printSCTE35(format string, pid uint16, msg scte35.SCTE35) {
+	if format == formatJSON {
+		emitJSON(events.NewSCTE35(pid, msg))
+		return
+	}
 	printlnf("SCTE35 Message on PID %d", pid)
 
 	printSpliceCommand(msg.CommandInfo())
@@ -305,7 +378,11 @@ func printSpliceInsertCommand(insert scte35.SpliceInsertCommand) {
 	}
 }
 
-func printPmt(pn uint16, pmt psi.PMT) {
+func printPmt(format string, pn uint16, pmt psi.PMT) {
+	if format == formatJSON {
+		emitJSON(events.NewPMT(pn, pmt))
+		return
+	}
 	printlnf("Program #%v PMT", pn)
 	printlnf("\tPIDs %v", pmt.Pids())
 	println("\tElementary Streams")
@@ -317,7 +394,11 @@ func printPmt(pn uint16, pmt psi.PMT) {
 	}
 }
 
-func printPat(pat psi.PAT) {
+func printPat(format string, pat psi.PAT) {
+	if format == formatJSON {
+		emitJSON(events.NewPAT(pat))
+		return
+	}
 	println("Pat")
 	printlnf("\tPMT PIDs %v", pat.ProgramMap())
 	printlnf("\tNumber of Programs %v", pat.NumPrograms())
@@ -326,3 +407,13 @@ func printPat(pat psi.PAT) {
 func printlnf(format string, a ...interface{}) {
 	fmt.Printf(format+"\n", a...)
 }
+
+// emitJSON writes v as a single line of NDJSON to stdout.
+func emitJSON(v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		fmt.Println("Cannot marshal event to JSON:", err)
+		return
+	}
+	fmt.Println(string(b))
+}
@@ -0,0 +1,244 @@
+/*
+MIT License
+
+Copyright 2016 Comcast Cable Communications Management, LLC
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+// Package events defines stable, JSON-marshalable records for the findings
+// gots tools surface: PAT/PMT structure, SCTE-35 signals, EBPs and PCR/PTS
+// timing. psi.PAT, psi.PMT, scte35.SCTE35 and ebp.EncoderBoundaryPoint are
+// interfaces without exported fields, so rather than pin down their
+// internal representations as a JSON API, each event type here is built
+// from one via a New function and owns its own wire format independently.
+//
+// This is a deliberate departure from adding MarshalJSON directly to those
+// original types: doing so isn't possible without exported fields to
+// marshal, and would couple the JSON wire format to gots's internal
+// representations. Callers that want NDJSON output should go through
+// events.New*, not expect psi/scte35/ebp themselves to be JSON-marshalable.
+package events
+
+import (
+	"fmt"
+
+	"github.com/Comcast/gots/ebp"
+	"github.com/Comcast/gots/psi"
+	"github.com/Comcast/gots/scte35"
+	"github.com/Comcast/gots/scte35/avail"
+)
+
+// Type identifies the kind of record an event carries, and is always present
+// as the "type" field of its JSON encoding.
+type Type string
+
+// The event types this package can emit.
+const (
+	TypePAT    Type = "pat"
+	TypePMT    Type = "pmt"
+	TypeSCTE35 Type = "scte35"
+	TypeEBP    Type = "ebp"
+	TypePCR    Type = "pcr"
+	TypeAvail  Type = "scte35_avail"
+)
+
+// PAT is emitted once per program association table.
+type PAT struct {
+	Type        Type              `json:"type"`
+	PMTPids     map[uint16]uint16 `json:"pmt_pids"`
+	NumPrograms int               `json:"num_programs"`
+}
+
+// NewPAT builds a PAT event from a parsed psi.PAT.
+func NewPAT(pat psi.PAT) PAT {
+	return PAT{
+		Type:        TypePAT,
+		PMTPids:     pat.ProgramMap(),
+		NumPrograms: pat.NumPrograms(),
+	}
+}
+
+// ElementaryStream is one entry of a PMT's elementary stream list.
+type ElementaryStream struct {
+	Pid                   uint16 `json:"pid"`
+	StreamType            uint8  `json:"stream_type"`
+	StreamTypeDescription string `json:"stream_type_description"`
+}
+
+// PMT is emitted once per program map table.
+type PMT struct {
+	Type    Type               `json:"type"`
+	Program uint16             `json:"program"`
+	Pids    []uint16           `json:"pids"`
+	Streams []ElementaryStream `json:"streams"`
+}
+
+// NewPMT builds a PMT event for program pn from a parsed psi.PMT.
+func NewPMT(pn uint16, pmt psi.PMT) PMT {
+	streams := make([]ElementaryStream, 0, len(pmt.ElementaryStreams()))
+	for _, es := range pmt.ElementaryStreams() {
+		streams = append(streams, ElementaryStream{
+			Pid:                   es.ElementaryPid(),
+			StreamType:            uint8(es.StreamType()),
+			StreamTypeDescription: es.StreamTypeDescription(),
+		})
+	}
+	return PMT{
+		Type:    TypePMT,
+		Program: pn,
+		Pids:    pmt.Pids(),
+		Streams: streams,
+	}
+}
+
+// SegmentationDescriptor is one SCTE-35 segmentation descriptor attached to
+// a splice message.
+type SegmentationDescriptor struct {
+	EventID         uint32  `json:"event_id"`
+	TypeID          uint8   `json:"type_id"`
+	TypeName        string  `json:"type_name"`
+	IsIn            bool    `json:"is_in"`
+	IsOut           bool    `json:"is_out"`
+	HasDuration     bool    `json:"has_duration"`
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+}
+
+// SCTE35 is emitted once per splice_info_section encountered on a SCTE-35 PID.
+type SCTE35 struct {
+	Type        Type    `json:"type"`
+	Pid         uint16  `json:"pid"`
+	CommandType string  `json:"command_type"`
+	HasPTS      bool    `json:"has_pts"`
+	PTS         uint64  `json:"pts,omitempty"`
+	EventID     *uint32 `json:"event_id,omitempty"`
+	HasDuration bool    `json:"has_duration,omitempty"`
+	// DurationSeconds is the splice_insert's own break_duration, set only
+	// when HasDuration is. It is not a segmentation descriptor, so it is
+	// kept out of Descriptors rather than appended as a synthetic entry.
+	DurationSeconds float64                  `json:"duration_seconds,omitempty"`
+	Descriptors     []SegmentationDescriptor `json:"descriptors,omitempty"`
+}
+
+// NewSCTE35 builds a SCTE35 event for a message seen on pid.
+func NewSCTE35(pid uint16, msg scte35.SCTE35) SCTE35 {
+	cmd := msg.CommandInfo()
+	event := SCTE35{
+		Type:        TypeSCTE35,
+		Pid:         pid,
+		CommandType: scte35.SpliceCommandTypeNames[cmd.CommandType()],
+		HasPTS:      cmd.HasPTS(),
+	}
+	if cmd.HasPTS() {
+		event.PTS = cmd.PTS()
+	}
+	if insert, ok := cmd.(scte35.SpliceInsertCommand); ok {
+		id := insert.EventID()
+		event.EventID = &id
+		event.HasDuration = insert.HasDuration()
+		if insert.HasDuration() {
+			event.DurationSeconds = insert.Duration().Seconds()
+		}
+	}
+	for _, segdesc := range msg.Descriptors() {
+		d := SegmentationDescriptor{
+			EventID:  segdesc.EventID(),
+			TypeID:   uint8(segdesc.TypeID()),
+			TypeName: scte35.SegDescTypeNames[segdesc.TypeID()],
+			IsIn:     segdesc.IsIn(),
+			IsOut:    segdesc.IsOut(),
+		}
+		if segdesc.HasDuration() {
+			d.HasDuration = true
+			d.DurationSeconds = segdesc.Duration().Seconds()
+		}
+		event.Descriptors = append(event.Descriptors, d)
+	}
+	return event
+}
+
+// EBP is emitted once per encoder boundary point encountered.
+type EBP struct {
+	Type   Type   `json:"type"`
+	Packet uint64 `json:"packet"`
+	Detail string `json:"detail"`
+}
+
+// NewEBP builds an EBP event for the boundary point found in packet number
+// packetNum. The EBP is rendered with %+v into Detail rather than picking
+// apart ebp.EncoderBoundaryPoint's accessors one by one, matching how the
+// CLI already logged these before this event type existed.
+func NewEBP(packetNum uint64, boundary ebp.EncoderBoundaryPoint) EBP {
+	return EBP{
+		Type:   TypeEBP,
+		Packet: packetNum,
+		Detail: fmt.Sprintf("%+v", boundary),
+	}
+}
+
+// PCR is emitted for every PCR the timing.Remapper rewrites.
+type PCR struct {
+	Type          Type   `json:"type"`
+	Pid           uint16 `json:"pid"`
+	PCR           uint64 `json:"pcr"`
+	NewPCR        uint64 `json:"new_pcr"`
+	Discontinuity bool   `json:"discontinuity"`
+}
+
+// NewPCR builds a PCR event.
+func NewPCR(pid uint16, pcr, newPCR uint64, discontinuity bool) PCR {
+	return PCR{
+		Type:          TypePCR,
+		Pid:           pid,
+		PCR:           pcr,
+		NewPCR:        newPCR,
+		Discontinuity: discontinuity,
+	}
+}
+
+// Avail is emitted for every avail.Correlator reports, whether closed by a
+// matching in descriptor or still open.
+type Avail struct {
+	Type                 Type    `json:"type"`
+	EventID              uint32  `json:"event_id"`
+	TypeID               string  `json:"type_id"`
+	Pid                  uint16  `json:"pid"`
+	OutPTS               uint64  `json:"out_pts"`
+	DeclaredDurationSecs float64 `json:"declared_duration_seconds,omitempty"`
+	Closed               bool    `json:"closed"`
+	InPTS                uint64  `json:"in_pts,omitempty"`
+	MeasuredDurationSecs float64 `json:"measured_duration_seconds,omitempty"`
+	DurationDeltaSecs    float64 `json:"duration_delta_seconds,omitempty"`
+}
+
+// NewAvail builds an Avail event from a correlated avail.Avail.
+func NewAvail(a avail.Avail) Avail {
+	return Avail{
+		Type:                 TypeAvail,
+		EventID:              a.EventID,
+		TypeID:               a.TypeID,
+		Pid:                  a.Pid,
+		OutPTS:               a.OutPTS,
+		DeclaredDurationSecs: a.DeclaredDuration.Seconds(),
+		Closed:               a.Closed,
+		InPTS:                a.InPTS,
+		MeasuredDurationSecs: a.MeasuredDuration.Seconds(),
+		DurationDeltaSecs:    a.DurationDelta.Seconds(),
+	}
+}
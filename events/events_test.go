@@ -0,0 +1,126 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Comcast/gots/scte35"
+	"github.com/Comcast/gots/scte35/avail"
+)
+
+type fakeSpliceCommand struct {
+	commandType scte35.SpliceCommandType
+	hasPTS      bool
+	pts         uint64
+}
+
+func (c fakeSpliceCommand) CommandType() scte35.SpliceCommandType { return c.commandType }
+func (c fakeSpliceCommand) HasPTS() bool                          { return c.hasPTS }
+func (c fakeSpliceCommand) PTS() uint64                           { return c.pts }
+
+type fakeSpliceInsert struct {
+	fakeSpliceCommand
+	eventID     uint32
+	hasDuration bool
+	duration    time.Duration
+}
+
+func (c fakeSpliceInsert) EventID() uint32         { return c.eventID }
+func (c fakeSpliceInsert) HasDuration() bool       { return c.hasDuration }
+func (c fakeSpliceInsert) Duration() time.Duration { return c.duration }
+
+type fakeSegDesc struct {
+	eventID     uint32
+	typeID      uint8
+	isOut, isIn bool
+	hasDuration bool
+	duration    time.Duration
+}
+
+func (d fakeSegDesc) EventID() uint32         { return d.eventID }
+func (d fakeSegDesc) TypeID() uint8           { return d.typeID }
+func (d fakeSegDesc) IsOut() bool             { return d.isOut }
+func (d fakeSegDesc) IsIn() bool              { return d.isIn }
+func (d fakeSegDesc) HasDuration() bool       { return d.hasDuration }
+func (d fakeSegDesc) Duration() time.Duration { return d.duration }
+
+type fakeMsg struct {
+	cmd   scte35.SpliceCommand
+	descs []scte35.SegmentationDescriptor
+}
+
+func (m fakeMsg) CommandInfo() scte35.SpliceCommand             { return m.cmd }
+func (m fakeMsg) Descriptors() []scte35.SegmentationDescriptor { return m.descs }
+
+// TestNewSCTE35DoesNotSmuggleBreakDurationIntoDescriptors guards against the
+// splice_insert's own break_duration being re-added as a fake segmentation
+// descriptor: Descriptors should only ever hold the real ones from
+// msg.Descriptors(), and the break_duration belongs in DurationSeconds.
+func TestNewSCTE35DoesNotSmuggleBreakDurationIntoDescriptors(t *testing.T) {
+	msg := fakeMsg{
+		cmd: fakeSpliceInsert{
+			eventID:     11,
+			hasDuration: true,
+			duration:    6 * time.Second,
+		},
+		descs: []scte35.SegmentationDescriptor{
+			fakeSegDesc{eventID: 99, typeID: 0x22, isOut: true},
+		},
+	}
+
+	event := NewSCTE35(256, msg)
+
+	if !event.HasDuration {
+		t.Error("expected HasDuration to be true")
+	}
+	if event.DurationSeconds != 6 {
+		t.Errorf("expected DurationSeconds 6, got %v", event.DurationSeconds)
+	}
+	if len(event.Descriptors) != 1 {
+		t.Fatalf("expected exactly the one real segmentation descriptor, got %d: %+v", len(event.Descriptors), event.Descriptors)
+	}
+	if got := event.Descriptors[0].EventID; got != 99 {
+		t.Errorf("expected the surviving descriptor to be the real one (event 99), got event %d", got)
+	}
+	if event.EventID == nil || *event.EventID != 11 {
+		t.Errorf("expected top-level EventID to be the splice_insert's own (11), got %v", event.EventID)
+	}
+}
+
+func TestNewAvail(t *testing.T) {
+	a := avail.Avail{
+		EventID:          42,
+		TypeID:           "0x34",
+		Pid:              256,
+		DeclaredDuration: 10 * time.Second,
+		Closed:           true,
+		MeasuredDuration: 11 * time.Second,
+		DurationDelta:    time.Second,
+	}
+
+	event := NewAvail(a)
+
+	if event.Type != TypeAvail {
+		t.Errorf("expected type %q, got %q", TypeAvail, event.Type)
+	}
+	if event.EventID != 42 || event.TypeID != "0x34" || event.Pid != 256 {
+		t.Errorf("unexpected identifying fields: %+v", event)
+	}
+	if !event.Closed {
+		t.Error("expected Closed to be true")
+	}
+	if event.DeclaredDurationSecs != 10 || event.MeasuredDurationSecs != 11 || event.DurationDeltaSecs != 1 {
+		t.Errorf("unexpected duration fields: %+v", event)
+	}
+}
+
+func TestNewPCR(t *testing.T) {
+	event := NewPCR(256, 1000, 2000, true)
+
+	if event.Type != TypePCR {
+		t.Errorf("expected type %q, got %q", TypePCR, event.Type)
+	}
+	if event.Pid != 256 || event.PCR != 1000 || event.NewPCR != 2000 || !event.Discontinuity {
+		t.Errorf("unexpected fields: %+v", event)
+	}
+}
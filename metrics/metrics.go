@@ -0,0 +1,220 @@
+/*
+MIT License
+
+Copyright 2016 Comcast Cable Communications Management, LLC
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+// Package metrics instruments a transport stream packet loop with
+// Prometheus counters, gauges and a histogram, so a long-running gots
+// process (rather than a one-shot file analysis) can be scraped by an
+// operator's existing monitoring stack. Metrics are kept on a private
+// prometheus.Registry rather than the global DefaultRegisterer so a
+// process can run more than one Metrics at a time.
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/Comcast/gots"
+	"github.com/Comcast/gots/packet"
+)
+
+// Metrics holds every counter/gauge/histogram this package exposes, plus
+// the small amount of per-PID state needed to derive them (previous
+// continuity counter, bytes seen and PCR since the last bitrate sample).
+type Metrics struct {
+	registry *prometheus.Registry
+
+	PacketsTotal                   *prometheus.CounterVec
+	ContinuityDiscontinuitiesTotal *prometheus.CounterVec
+	PCRJitterSeconds               prometheus.Histogram
+	PCRDiscontinuitiesTotal        prometheus.Counter
+	PTSDiscontinuitiesTotal        prometheus.Counter
+	SCTE35MessagesTotal            *prometheus.CounterVec
+	EBPTotal                       prometheus.Counter
+	BitrateBps                     *prometheus.GaugeVec
+
+	lastContinuity map[uint16]uint8
+	bytesSincePCR  map[uint16]uint64
+	lastPCR        map[uint16]uint64
+	lastPCRTime    map[uint16]time.Time
+
+	otel *otelInstruments
+}
+
+// New creates a Metrics with all series registered on a fresh registry.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		PacketsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gots_packets_total",
+			Help: "Transport stream packets seen, by PID.",
+		}, []string{"pid"}),
+		ContinuityDiscontinuitiesTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gots_continuity_discontinuities_total",
+			Help: "Continuity-counter discontinuities seen, by PID.",
+		}, []string{"pid"}),
+		PCRJitterSeconds: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "gots_pcr_jitter_seconds",
+			Help:    "Difference between the PCR delta and the wall-clock delta between consecutive PCRs on the same PID.",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 2, 16),
+		}),
+		PCRDiscontinuitiesTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "gots_pcr_discontinuities_total",
+			Help: "PCR discontinuities detected.",
+		}),
+		PTSDiscontinuitiesTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "gots_pts_discontinuities_total",
+			Help: "PTS discontinuities detected.",
+		}),
+		SCTE35MessagesTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gots_scte35_messages_total",
+			Help: "SCTE-35 splice_info_sections seen, by splice command type.",
+		}, []string{"command_type"}),
+		EBPTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "gots_ebp_total",
+			Help: "Encoder boundary points seen.",
+		}),
+		BitrateBps: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "gots_bitrate_bps",
+			Help: "Bitrate, in bits per second, computed from PCR deltas, by PID.",
+		}, []string{"pid"}),
+
+		lastContinuity: make(map[uint16]uint8),
+		bytesSincePCR:  make(map[uint16]uint64),
+		lastPCR:        make(map[uint16]uint64),
+		lastPCRTime:    make(map[uint16]time.Time),
+	}
+
+	m.registry.MustRegister(
+		m.PacketsTotal,
+		m.ContinuityDiscontinuitiesTotal,
+		m.PCRJitterSeconds,
+		m.PCRDiscontinuitiesTotal,
+		m.PTSDiscontinuitiesTotal,
+		m.SCTE35MessagesTotal,
+		m.EBPTotal,
+		m.BitrateBps,
+	)
+
+	return m
+}
+
+// ObservePacket updates PacketsTotal, ContinuityDiscontinuitiesTotal and the
+// byte counter that feeds BitrateBps for a single packet.
+func (m *Metrics) ObservePacket(pkt packet.Packet) {
+	pid, err := packet.Pid(pkt)
+	if err != nil {
+		return
+	}
+	label := fmt.Sprint(pid)
+	m.PacketsTotal.WithLabelValues(label).Inc()
+	m.bytesSincePCR[pid] += uint64(len(pkt))
+
+	// continuity_counter is the low 4 bits of the 4th TS header byte, and
+	// only increments when the packet carries a payload.
+	hasPayload := pkt[3]&0x10 != 0
+	if !hasPayload {
+		return
+	}
+	cc := pkt[3] & 0x0F
+	if last, ok := m.lastContinuity[pid]; ok && cc != (last+1)&0x0F {
+		m.ContinuityDiscontinuitiesTotal.WithLabelValues(label).Inc()
+	}
+	m.lastContinuity[pid] = cc
+}
+
+// ObservePCR updates PCRDiscontinuitiesTotal, PCRJitterSeconds and
+// BitrateBps for a PCR that timing.Remapper just rewrote. discontinuity
+// should be true whenever remapper.Stats().PCRDiscontinuities changed as a
+// result of this PCR.
+func (m *Metrics) ObservePCR(pid uint16, pcr uint64, discontinuity bool) {
+	now := time.Now()
+	if discontinuity {
+		m.PCRDiscontinuitiesTotal.Inc()
+	}
+
+	if last, ok := m.lastPCR[pid]; ok && !discontinuity {
+		pcrDelta := float64(pcr-last) / gots.PcrClockRate
+		wallDelta := now.Sub(m.lastPCRTime[pid]).Seconds()
+		m.PCRJitterSeconds.Observe(pcrDelta - wallDelta)
+
+		if pcrDelta > 0 {
+			label := fmt.Sprint(pid)
+			bits := float64(m.bytesSincePCR[pid]) * 8
+			m.BitrateBps.WithLabelValues(label).Set(bits / pcrDelta)
+		}
+		m.bytesSincePCR[pid] = 0
+	}
+
+	m.lastPCR[pid] = pcr
+	m.lastPCRTime[pid] = now
+
+	if m.otel != nil {
+		m.otel.observePCR(pid, discontinuity)
+	}
+}
+
+// ObservePTSDiscontinuity increments PTSDiscontinuitiesTotal.
+func (m *Metrics) ObservePTSDiscontinuity() {
+	m.PTSDiscontinuitiesTotal.Inc()
+	if m.otel != nil {
+		m.otel.observePTSDiscontinuity()
+	}
+}
+
+// ObserveSCTE35 increments SCTE35MessagesTotal for commandType (e.g.
+// "splice_insert").
+func (m *Metrics) ObserveSCTE35(commandType string) {
+	m.SCTE35MessagesTotal.WithLabelValues(commandType).Inc()
+	if m.otel != nil {
+		m.otel.observeSCTE35(commandType)
+	}
+}
+
+// ObserveEBP increments EBPTotal.
+func (m *Metrics) ObserveEBP() {
+	m.EBPTotal.Inc()
+	if m.otel != nil {
+		m.otel.observeEBP()
+	}
+}
+
+// Handler returns an http.Handler serving this Metrics's series in the
+// Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ListenAndServe serves Handler at /metrics on addr (e.g. ":9090"). It
+// blocks, so callers that also want to process packets should run it in a
+// goroutine.
+func (m *Metrics) ListenAndServe(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	return http.ListenAndServe(addr, mux)
+}
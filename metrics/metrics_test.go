@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/Comcast/gots/packet"
+)
+
+// tsPacket builds a minimal transport stream packet carrying a payload, for
+// the PID and continuity_counter ObservePacket cares about.
+func tsPacket(pid uint16, cc byte) packet.Packet {
+	buf := make([]byte, packet.PacketSize)
+	buf[0] = 0x47
+	buf[1] = byte(pid >> 8 & 0x1F)
+	buf[2] = byte(pid & 0xFF)
+	buf[3] = 0x10 | (cc & 0x0F) // adaptation_field_control=01 (payload only)
+	return packet.Packet(buf)
+}
+
+func TestObservePacketCountsContinuityDiscontinuities(t *testing.T) {
+	m := New()
+
+	m.ObservePacket(tsPacket(256, 0))
+	m.ObservePacket(tsPacket(256, 1))
+	// Jump from 1 straight to 3, skipping the expected 2.
+	m.ObservePacket(tsPacket(256, 3))
+
+	if got := testutil.ToFloat64(m.PacketsTotal.WithLabelValues("256")); got != 3 {
+		t.Errorf("expected 3 packets counted, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.ContinuityDiscontinuitiesTotal.WithLabelValues("256")); got != 1 {
+		t.Errorf("expected 1 continuity discontinuity, got %v", got)
+	}
+}
+
+func TestObservePCRCountsDiscontinuities(t *testing.T) {
+	m := New()
+
+	m.ObservePCR(256, 1000, false)
+	m.ObservePCR(256, 2000, true)
+
+	if got := testutil.ToFloat64(m.PCRDiscontinuitiesTotal); got != 1 {
+		t.Errorf("expected 1 PCR discontinuity, got %v", got)
+	}
+}
+
+func TestObservePTSDiscontinuityAndSCTE35AndEBPIncrementCounters(t *testing.T) {
+	m := New()
+
+	m.ObservePTSDiscontinuity()
+	m.ObservePTSDiscontinuity()
+	if got := testutil.ToFloat64(m.PTSDiscontinuitiesTotal); got != 2 {
+		t.Errorf("expected 2 PTS discontinuities, got %v", got)
+	}
+
+	m.ObserveSCTE35("splice_insert")
+	if got := testutil.ToFloat64(m.SCTE35MessagesTotal.WithLabelValues("splice_insert")); got != 1 {
+		t.Errorf("expected 1 splice_insert message, got %v", got)
+	}
+
+	m.ObserveEBP()
+	if got := testutil.ToFloat64(m.EBPTotal); got != 1 {
+		t.Errorf("expected 1 EBP, got %v", got)
+	}
+}
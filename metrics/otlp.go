@@ -0,0 +1,101 @@
+/*
+MIT License
+
+Copyright 2016 Comcast Cable Communications Management, LLC
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// otelInstruments mirrors the subset of Metrics' series that are cheap to
+// re-derive as OTel counters for users who push to a collector instead of,
+// or in addition to, scraping Prometheus. It deliberately skips the bitrate
+// gauge and the jitter histogram; those are Prometheus-only.
+type otelInstruments struct {
+	meter                   metric.Meter
+	pcrDiscontinuities      metric.Int64Counter
+	ptsDiscontinuities      metric.Int64Counter
+	scte35MessagesByCommand metric.Int64Counter
+	ebpTotal                metric.Int64Counter
+}
+
+// EnableOTLP starts pushing PCR/PTS discontinuity, SCTE-35 and EBP counts
+// to an OTLP/gRPC collector at endpoint (host:port), in addition to
+// whatever this Metrics already exposes over Handler/ListenAndServe. It
+// returns a shutdown func that flushes and stops the exporter.
+func (m *Metrics) EnableOTLP(ctx context.Context, endpoint string) (shutdown func(context.Context) error, err error) {
+	exporter, err := otlpmetricgrpc.New(ctx,
+		otlpmetricgrpc.WithEndpoint(endpoint),
+		otlpmetricgrpc.WithInsecure(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("metrics: creating OTLP exporter: %w", err)
+	}
+
+	provider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)),
+	)
+	meter := provider.Meter("github.com/Comcast/gots/metrics")
+
+	oi := &otelInstruments{meter: meter}
+	if oi.pcrDiscontinuities, err = meter.Int64Counter("gots.pcr_discontinuities"); err != nil {
+		return nil, err
+	}
+	if oi.ptsDiscontinuities, err = meter.Int64Counter("gots.pts_discontinuities"); err != nil {
+		return nil, err
+	}
+	if oi.scte35MessagesByCommand, err = meter.Int64Counter("gots.scte35_messages"); err != nil {
+		return nil, err
+	}
+	if oi.ebpTotal, err = meter.Int64Counter("gots.ebp"); err != nil {
+		return nil, err
+	}
+
+	m.otel = oi
+	return provider.Shutdown, nil
+}
+
+func (o *otelInstruments) observePCR(pid uint16, discontinuity bool) {
+	if discontinuity {
+		o.pcrDiscontinuities.Add(context.Background(), 1, metric.WithAttributes(attribute.Int("pid", int(pid))))
+	}
+}
+
+func (o *otelInstruments) observePTSDiscontinuity() {
+	o.ptsDiscontinuities.Add(context.Background(), 1)
+}
+
+func (o *otelInstruments) observeSCTE35(commandType string) {
+	o.scte35MessagesByCommand.Add(context.Background(), 1, metric.WithAttributes(attribute.String("command_type", commandType)))
+}
+
+func (o *otelInstruments) observeEBP() {
+	o.ebpTotal.Add(context.Background(), 1)
+}
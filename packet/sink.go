@@ -0,0 +1,83 @@
+/*
+MIT License
+
+Copyright 2016 Comcast Cable Communications Management, LLC
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package packet
+
+import (
+	"net"
+	"os"
+	"strings"
+)
+
+// Sink is anything a transport stream can be written to: a file, stdout, or
+// a live UDP push. It is just an io.Writer plus Close, so a file, stdout and
+// a UDP peer are all written to with the same sink.Write(pkt) call.
+type Sink interface {
+	Write(p []byte) (n int, err error)
+	Close() error
+}
+
+// OpenSink opens a Sink for uri:
+//
+//	"-"               stdout
+//	"udp://host:port" push each write as a UDP datagram to host:port
+//	anything else     treated as a file path, truncated and created if needed
+func OpenSink(uri string) (Sink, error) {
+	switch {
+	case uri == "-":
+		return stdoutSink{}, nil
+	case strings.HasPrefix(uri, "udp://"):
+		return newUDPSink(uri)
+	default:
+		return os.OpenFile(uri, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
+	}
+}
+
+// stdoutSink writes to os.Stdout. Close is a no-op for the same reason as
+// stdinSource.Close.
+type stdoutSink struct{}
+
+func (stdoutSink) Write(p []byte) (int, error) { return os.Stdout.Write(p) }
+func (stdoutSink) Close() error                { return nil }
+
+// udpSink pushes every write as a single UDP datagram to a fixed peer.
+type udpSink struct {
+	conn *net.UDPConn
+}
+
+func newUDPSink(uri string) (Sink, error) {
+	host := strings.TrimPrefix(uri, "udp://")
+	addr, err := net.ResolveUDPAddr("udp", host)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return nil, err
+	}
+	return &udpSink{conn: conn}, nil
+}
+
+func (s *udpSink) Write(p []byte) (int, error) { return s.conn.Write(p) }
+func (s *udpSink) Close() error                { return s.conn.Close() }
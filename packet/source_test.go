@@ -0,0 +1,80 @@
+package packet
+
+import (
+	"io"
+	"net"
+	"testing"
+)
+
+func TestUDPSourceReassemblesPacketsAcrossDatagrams(t *testing.T) {
+	listener, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatalf("ListenUDP: %v", err)
+	}
+	defer listener.Close()
+
+	src := &udpSource{conn: listener, buf: make([]byte, 64*1024)}
+
+	sender, err := net.DialUDP("udp", nil, listener.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatalf("DialUDP: %v", err)
+	}
+	defer sender.Close()
+
+	want := make([]byte, 3*PacketSize)
+	for i := range want {
+		want[i] = byte(i)
+	}
+	// Split across two datagrams that don't align on a packet boundary.
+	if _, err := sender.Write(want[:250]); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if _, err := sender.Write(want[250:]); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(src, got); err != nil {
+		t.Fatalf("ReadFull: %v", err)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("byte %d: got %d, want %d", i, got[i], want[i])
+		}
+	}
+}
+
+func TestUDPSourceStripsRTPHeaderPerDatagram(t *testing.T) {
+	listener, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatalf("ListenUDP: %v", err)
+	}
+	defer listener.Close()
+
+	src := &udpSource{conn: listener, rtp: true, buf: make([]byte, 64*1024)}
+
+	sender, err := net.DialUDP("udp", nil, listener.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatalf("DialUDP: %v", err)
+	}
+	defer sender.Close()
+
+	payload := make([]byte, PacketSize)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+	datagram := append(make([]byte, rtpHeaderSize), payload...)
+	if _, err := sender.Write(datagram); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	got := make([]byte, PacketSize)
+	if _, err := io.ReadFull(src, got); err != nil {
+		t.Fatalf("ReadFull: %v", err)
+	}
+	for i := range payload {
+		if got[i] != payload[i] {
+			t.Fatalf("byte %d: got %d, want %d", i, got[i], payload[i])
+		}
+	}
+}
@@ -0,0 +1,145 @@
+/*
+MIT License
+
+Copyright 2016 Comcast Cable Communications Management, LLC
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+// Package avail correlates SCTE-35 out/in segmentation descriptor pairs
+// into avails, so callers can see whether an ad break that was signalled as
+// open was ever closed, and whether it ran for as long as it declared it
+// would.
+package avail
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Comcast/gots"
+	"github.com/Comcast/gots/scte35"
+)
+
+// Avail is one ad avail: a Program/Provider Placement Opportunity (or
+// equivalent) Start descriptor, and the in descriptor with the same EventID
+// that closed it, if one has arrived yet. The in descriptor's TypeID is not
+// compared.
+type Avail struct {
+	// EventID is the segmentation_event_id shared by the out and in descriptors.
+	EventID uint32
+	// TypeID is the out descriptor's segmentation_type_id, in decimal.
+	TypeID string
+	// Pid is the PID the out descriptor was seen on.
+	Pid uint16
+
+	OutPTS           uint64
+	DeclaredDuration time.Duration
+
+	// Closed is true once the matching in descriptor has arrived.
+	Closed           bool
+	InPTS            uint64
+	MeasuredDuration time.Duration
+	// DurationDelta is MeasuredDuration - DeclaredDuration, valid only when
+	// Closed and DeclaredDuration are both set.
+	DurationDelta time.Duration
+}
+
+// Correlator tracks outstanding SCTE-35 avails and pairs each out
+// descriptor with the in descriptor, if any, that later closes it.
+// A Correlator is not safe for concurrent use.
+type Correlator struct {
+	pending map[string]*Avail
+}
+
+// NewCorrelator creates an empty Correlator.
+func NewCorrelator() *Correlator {
+	return &Correlator{pending: make(map[string]*Avail)}
+}
+
+// Feed processes the segmentation descriptors of msg, seen on pid, and
+// returns the avails that were closed by an in descriptor in this message,
+// if any. The PTS recorded for each descriptor is the splice command's own
+// splice_time, when present.
+func (c *Correlator) Feed(pid uint16, msg scte35.SCTE35) []Avail {
+	var pts uint64
+	if cmd := msg.CommandInfo(); cmd.HasPTS() {
+		pts = cmd.PTS()
+	}
+
+	var closedNow []Avail
+	for _, segdesc := range msg.Descriptors() {
+		// A Start and its matching End descriptor share EventID() but have
+		// different TypeID()s (e.g. Provider PO Start 0x34 is closed by
+		// Provider PO End 0x35), so the pending map is keyed by EventID()
+		// alone; TypeID is recorded on the Avail only for reporting.
+		k := pendingKey(segdesc.EventID())
+		switch {
+		case segdesc.IsOut():
+			a := &Avail{
+				EventID: segdesc.EventID(),
+				TypeID:  fmt.Sprintf("%v", segdesc.TypeID()),
+				Pid:     pid,
+				OutPTS:  pts,
+			}
+			if segdesc.HasDuration() {
+				a.DeclaredDuration = segdesc.Duration()
+			}
+			c.pending[k] = a
+		case segdesc.IsIn():
+			a, ok := c.pending[k]
+			if !ok {
+				continue
+			}
+			a.Closed = true
+			a.InPTS = pts
+			a.MeasuredDuration = ptsDelta(a.OutPTS, pts)
+			if a.DeclaredDuration > 0 {
+				a.DurationDelta = a.MeasuredDuration - a.DeclaredDuration
+			}
+			closedNow = append(closedNow, *a)
+			delete(c.pending, k)
+		}
+	}
+	return closedNow
+}
+
+// OpenAvails returns the outs that have not yet been matched with an in.
+// Call this at EOF to find avails that never closed.
+func (c *Correlator) OpenAvails() []Avail {
+	open := make([]Avail, 0, len(c.pending))
+	for _, a := range c.pending {
+		open = append(open, *a)
+	}
+	return open
+}
+
+func pendingKey(eventID uint32) string {
+	return fmt.Sprintf("%d", eventID)
+}
+
+// ptsDelta returns the duration between two 90kHz PTS values, accounting
+// for the 33-bit PTS rolling over.
+func ptsDelta(out, in uint64) time.Duration {
+	const ptsMax = uint64(1) << 33
+	delta := in - out
+	if in < out {
+		delta = (ptsMax - out) + in
+	}
+	return time.Duration(float64(delta) / float64(gots.PtsClockRate) * float64(time.Second))
+}
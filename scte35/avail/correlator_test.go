@@ -0,0 +1,106 @@
+package avail
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Comcast/gots"
+	"github.com/Comcast/gots/scte35"
+)
+
+type fakeSpliceCommand struct {
+	hasPTS bool
+	pts    uint64
+}
+
+func (c fakeSpliceCommand) CommandType() scte35.SpliceCommandType { return 0 }
+func (c fakeSpliceCommand) HasPTS() bool                          { return c.hasPTS }
+func (c fakeSpliceCommand) PTS() uint64                           { return c.pts }
+
+type fakeSegDesc struct {
+	eventID     uint32
+	typeID      uint8
+	isOut, isIn bool
+	hasDuration bool
+	duration    time.Duration
+}
+
+func (d fakeSegDesc) EventID() uint32         { return d.eventID }
+func (d fakeSegDesc) TypeID() uint8           { return d.typeID }
+func (d fakeSegDesc) IsOut() bool             { return d.isOut }
+func (d fakeSegDesc) IsIn() bool              { return d.isIn }
+func (d fakeSegDesc) HasDuration() bool       { return d.hasDuration }
+func (d fakeSegDesc) Duration() time.Duration { return d.duration }
+
+type fakeMsg struct {
+	cmd   fakeSpliceCommand
+	descs []scte35.SegmentationDescriptor
+}
+
+func (m fakeMsg) CommandInfo() scte35.SpliceCommand             { return m.cmd }
+func (m fakeMsg) Descriptors() []scte35.SegmentationDescriptor { return m.descs }
+
+// Provider Placement Opportunity Start/End, per the SCTE-35 segmentation_type_id table.
+const (
+	typeProviderPOStart = 0x34
+	typeProviderPOEnd   = 0x35
+)
+
+func ptsAt(seconds float64) uint64 {
+	return uint64(seconds * gots.PtsClockRate)
+}
+
+func TestCorrelatorMatchesOutAndInByEventIDAcrossDifferentTypeIDs(t *testing.T) {
+	c := NewCorrelator()
+
+	out := fakeMsg{
+		cmd: fakeSpliceCommand{hasPTS: true, pts: ptsAt(5)},
+		descs: []scte35.SegmentationDescriptor{
+			fakeSegDesc{eventID: 42, typeID: typeProviderPOStart, isOut: true, hasDuration: true, duration: 10 * time.Second},
+		},
+	}
+	if closed := c.Feed(256, out); len(closed) != 0 {
+		t.Fatalf("expected no avails closed by the out message, got %d", len(closed))
+	}
+	if open := c.OpenAvails(); len(open) != 1 || open[0].EventID != 42 {
+		t.Fatalf("expected event 42 to be pending, got %+v", open)
+	}
+
+	in := fakeMsg{
+		cmd: fakeSpliceCommand{hasPTS: true, pts: ptsAt(15)},
+		descs: []scte35.SegmentationDescriptor{
+			fakeSegDesc{eventID: 42, typeID: typeProviderPOEnd, isIn: true},
+		},
+	}
+	closed := c.Feed(256, in)
+	if len(closed) != 1 {
+		t.Fatalf("expected the in message (different TypeID, same EventID) to close the pending avail, got %d closed", len(closed))
+	}
+	if !closed[0].Closed {
+		t.Error("expected Closed to be true")
+	}
+	if closed[0].MeasuredDuration != 10*time.Second {
+		t.Errorf("expected measured duration of 10s, got %v", closed[0].MeasuredDuration)
+	}
+	if closed[0].DurationDelta != 0 {
+		t.Errorf("expected a zero duration delta when measured matches declared, got %v", closed[0].DurationDelta)
+	}
+	if len(c.OpenAvails()) != 0 {
+		t.Errorf("expected no avails left pending after the in arrived, got %d", len(c.OpenAvails()))
+	}
+}
+
+func TestCorrelatorReportsUnmatchedOutsAsOpenAtEOF(t *testing.T) {
+	c := NewCorrelator()
+	out := fakeMsg{
+		descs: []scte35.SegmentationDescriptor{
+			fakeSegDesc{eventID: 7, typeID: typeProviderPOStart, isOut: true},
+		},
+	}
+	c.Feed(256, out)
+
+	open := c.OpenAvails()
+	if len(open) != 1 || open[0].EventID != 7 || open[0].Closed {
+		t.Fatalf("expected event 7 to still be open, got %+v", open)
+	}
+}
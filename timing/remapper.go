@@ -0,0 +1,199 @@
+/*
+MIT License
+
+Copyright 2016 Comcast Cable Communications Management, LLC
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+// Package timing provides PCR/PTS/DTS remapping for transport streams whose
+// timestamps are discontinuous, e.g. streams produced by concatenating or
+// splicing together multiple encodes. A Remapper holds an internal offset
+// that it applies to every PCR, PTS and DTS it sees so that the values it
+// produces increase monotonically, and adjusts that offset whenever it
+// detects a discontinuity in the incoming stream.
+package timing
+
+import (
+	"github.com/Comcast/gots"
+	"github.com/Comcast/gots/packet"
+	"github.com/Comcast/gots/packet/adaptationfield"
+	"github.com/Comcast/gots/pes"
+)
+
+// Config holds the thresholds a Remapper uses to decide whether a jump in
+// PCR or PTS/DTS is a genuine discontinuity rather than ordinary clock drift.
+type Config struct {
+	// PCRJumpWindow is the largest forward jump, in 27MHz PCR ticks,
+	// between consecutive PCRs before it is treated as a discontinuity.
+	// Any backwards jump is always a discontinuity.
+	PCRJumpWindow uint64
+	// PTSWrapTolerance is the largest forward or backward delta, in 90kHz
+	// PTS ticks, between consecutive PTS values on a PID before it is
+	// treated as a discontinuity.
+	PTSWrapTolerance uint64
+}
+
+// DefaultConfig returns the thresholds used by the gots CLI's -timing flag.
+func DefaultConfig() Config {
+	return Config{
+		PCRJumpWindow:    2 * gots.PcrClockRate,
+		PTSWrapTolerance: gots.PtsClockRate,
+	}
+}
+
+// Stats reports what a Remapper has done to the packets fed to it so far.
+type Stats struct {
+	// PCRDiscontinuities is the number of PCR discontinuities detected.
+	PCRDiscontinuities uint64
+	// PTSDiscontinuities is the number of PTS discontinuities detected.
+	PTSDiscontinuities uint64
+	// TotalOffsetApplied is the sum, in 27MHz PCR ticks, of the absolute
+	// size of every offset change made in response to a PCR discontinuity.
+	TotalOffsetApplied int64
+}
+
+// Remapper rewrites the PCR, PTS and DTS values of the packets fed to it so
+// that they are monotonically continuous, holding and adjusting an internal
+// offset across discontinuities. A Remapper is not safe for concurrent use.
+type Remapper struct {
+	cfg Config
+
+	prevPCR, prevNewPCR       uint64
+	currentOffset, lastOffset int64
+	prevPTS                   map[uint16]uint64
+
+	stats Stats
+}
+
+// NewRemapper creates a Remapper using the given Config.
+func NewRemapper(cfg Config) *Remapper {
+	return &Remapper{
+		cfg:     cfg,
+		prevPTS: make(map[uint16]uint64),
+	}
+}
+
+// NewDefaultRemapper creates a Remapper using DefaultConfig.
+func NewDefaultRemapper() *Remapper {
+	return NewRemapper(DefaultConfig())
+}
+
+// Feed rewrites the PCR, PTS and DTS of pkt in place, and returns it. Packets
+// without a PCR or a PES header carrying a PTS/DTS are returned unmodified.
+func (r *Remapper) Feed(pkt packet.Packet) (packet.Packet, error) {
+	if ad, _ := packet.ContainsAdaptationField(pkt); ad && adaptationfield.HasPCR(pkt) {
+		pcrBytes, err := adaptationfield.PCR(pkt)
+		if err != nil {
+			return pkt, err
+		}
+		newPCR := r.remapPCR(gots.ExtractPCR(pcrBytes))
+		gots.InsertPCR(pcrBytes, uint64(newPCR))
+		r.prevNewPCR = uint64(newPCR)
+	}
+
+	if es, err := packet.PESHeader(pkt); err == nil {
+		h, err := pes.NewPESHeader(es)
+		if err != nil {
+			return pkt, nil
+		}
+		pid, err := packet.Pid(pkt)
+		if err != nil {
+			return pkt, err
+		}
+		if h.HasPTS() {
+			newPTS := r.remapPTS(pid, h.PTS())
+			gots.InsertPTS(es[9:14], uint64(newPTS))
+		}
+		if h.HasDTS() && h.DTS() != 0 {
+			newDTS := r.remapDTS(h.DTS())
+			gots.InsertPTS(es[14:19], uint64(newDTS))
+		}
+	}
+
+	return pkt, nil
+}
+
+// remapPCR computes the remapped PCR for pcr, updating the held offset and
+// discontinuity stats as a side effect. It is kept free of packet and
+// adaptationfield byte-twiddling so the heuristic can be tested directly.
+func (r *Remapper) remapPCR(pcr uint64) gots.PCR {
+	switch {
+	case r.prevPCR == 0 && r.currentOffset == 0:
+		r.currentOffset = -int64(pcr) + (1 * gots.PcrClockRate)
+	case r.prevPCR != 0 && (pcr > r.prevPCR+r.cfg.PCRJumpWindow || pcr < r.prevPCR):
+		r.stats.PCRDiscontinuities++
+		r.lastOffset = r.currentOffset
+		r.currentOffset = -int64(pcr) + int64(r.prevNewPCR) + (0.25 * gots.PcrClockRate)
+		r.stats.TotalOffsetApplied += abs64(r.currentOffset - r.lastOffset)
+	}
+	r.prevPCR = pcr
+
+	return gots.PCR(pcr).Add(gots.PCR(r.currentOffset))
+}
+
+// remapPTS computes the remapped PTS for pid's pts, updating the held offset
+// and discontinuity stats as a side effect.
+func (r *Remapper) remapPTS(pid uint16, pts uint64) gots.PTS {
+	if r.prevPCR == 0 && r.currentOffset == 0 {
+		r.currentOffset = -int64(pts*300) + (1 * gots.PcrClockRate)
+	}
+
+	prev := r.prevPTS[pid]
+	if prev != 0 && (pts > prev+r.cfg.PTSWrapTolerance || pts+r.cfg.PTSWrapTolerance < prev) {
+		r.stats.PTSDiscontinuities++
+	}
+	r.prevPTS[pid] = pts
+
+	newPTS := gots.PTS(pts).Add(gots.PTS(r.currentOffset / 300))
+	if r.prevNewPCR != 0 && uint64(newPTS) > (r.prevNewPCR/300)+2*gots.PtsClockRate {
+		newPTS = gots.PTS(pts).Add(gots.PTS(r.lastOffset / 300))
+	}
+	return newPTS
+}
+
+// remapDTS computes the remapped DTS using the currently held offset. DTS
+// never drives discontinuity detection; that is always decided by PCR/PTS.
+func (r *Remapper) remapDTS(dts uint64) gots.PTS {
+	newDTS := gots.PTS(dts).Add(gots.PTS(r.currentOffset / 300))
+	if uint64(newDTS) > (r.prevNewPCR/300)+2*gots.PtsClockRate {
+		newDTS = gots.PTS(dts).Add(gots.PTS(r.lastOffset / 300))
+	}
+	return newDTS
+}
+
+// Stats returns a snapshot of the discontinuities detected and offset
+// applied so far.
+func (r *Remapper) Stats() Stats {
+	return r.stats
+}
+
+// LastPCR returns the most recently fed PCR and the value it was remapped
+// to, for callers that want to report on individual PCRs rather than just
+// aggregate Stats.
+func (r *Remapper) LastPCR() (pcr, newPCR uint64) {
+	return r.prevPCR, r.prevNewPCR
+}
+
+func abs64(i int64) int64 {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
@@ -0,0 +1,69 @@
+package timing
+
+import (
+	"testing"
+
+	"github.com/Comcast/gots"
+)
+
+func TestRemapPCRFirstPacketEstablishesOffset(t *testing.T) {
+	r := NewDefaultRemapper()
+	newPCR := r.remapPCR(12345)
+	if newPCR != gots.PCR(gots.PcrClockRate) {
+		t.Errorf("expected first PCR to be remapped to %d, got %d", gots.PcrClockRate, newPCR)
+	}
+	if r.stats.PCRDiscontinuities != 0 {
+		t.Errorf("first packet must not count as a discontinuity, got %d", r.stats.PCRDiscontinuities)
+	}
+}
+
+func TestRemapPCRNoDiscontinuityWithinWindow(t *testing.T) {
+	r := NewDefaultRemapper()
+	r.remapPCR(gots.PcrClockRate)
+	r.remapPCR(gots.PcrClockRate + gots.PcrClockRate/2)
+	if r.stats.PCRDiscontinuities != 0 {
+		t.Errorf("expected no discontinuity for a forward jump inside PCRJumpWindow, got %d", r.stats.PCRDiscontinuities)
+	}
+}
+
+func TestRemapPCRForwardJumpBeyondWindowIsDiscontinuity(t *testing.T) {
+	r := NewDefaultRemapper()
+	r.remapPCR(gots.PcrClockRate)
+	r.remapPCR(gots.PcrClockRate + 3*gots.PcrClockRate)
+	if r.stats.PCRDiscontinuities != 1 {
+		t.Errorf("expected 1 discontinuity, got %d", r.stats.PCRDiscontinuities)
+	}
+	if r.stats.TotalOffsetApplied == 0 {
+		t.Error("expected TotalOffsetApplied to be updated on discontinuity")
+	}
+}
+
+func TestRemapPCRBackwardsJumpIsDiscontinuity(t *testing.T) {
+	r := NewDefaultRemapper()
+	r.remapPCR(2 * gots.PcrClockRate)
+	r.remapPCR(gots.PcrClockRate)
+	if r.stats.PCRDiscontinuities != 1 {
+		t.Errorf("expected a backwards PCR to always count as a discontinuity, got %d", r.stats.PCRDiscontinuities)
+	}
+}
+
+func TestRemapPTSDiscontinuityPerPID(t *testing.T) {
+	r := NewDefaultRemapper()
+	r.remapPTS(256, gots.PtsClockRate)
+	r.remapPTS(257, gots.PtsClockRate)
+	r.remapPTS(256, gots.PtsClockRate+3*gots.PtsClockRate)
+	if r.stats.PTSDiscontinuities != 1 {
+		t.Errorf("expected 1 PTS discontinuity tracked independently per PID, got %d", r.stats.PTSDiscontinuities)
+	}
+}
+
+func TestStatsIsASnapshot(t *testing.T) {
+	r := NewDefaultRemapper()
+	r.remapPCR(gots.PcrClockRate)
+	r.remapPCR(gots.PcrClockRate + 3*gots.PcrClockRate)
+	snapshot := r.Stats()
+	r.remapPCR(uint64(snapshot.TotalOffsetApplied) + 10*gots.PcrClockRate)
+	if snapshot.PCRDiscontinuities != 1 {
+		t.Errorf("Stats() snapshot should not change after later Feed calls, got %d", snapshot.PCRDiscontinuities)
+	}
+}